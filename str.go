@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value returns the unquoted, unescaped payload of a rulestring token.
+// Bare tokens ([a-z0-9]+) are returned verbatim; single-quoted tokens have
+// their surrounding quotes stripped and any '\x' escape resolved to 'x'.
+func (p *Str) Value(t token32) string {
+	raw := string(p.buffer[t.begin:t.end])
+	if len(raw) < 2 || raw[0] != '\'' {
+		return raw
+	}
+
+	body := raw[1 : len(raw)-1]
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] == '\\' && i+1 < len(body) {
+			i++
+		}
+		b.WriteByte(body[i])
+	}
+	return b.String()
+}
+
+// KV is a single parsed key/value pair. Value holds an int64, float64,
+// bool, time.Time or string depending on which literal matched the rhs.
+type KV struct {
+	Key   string
+	Value any
+}
+
+// Pairs parses p.Buffer and decodes every kv into a typed KV, converting
+// integer, float, bool and datetime literals to their Go equivalents and
+// leaving quoted/bare strings as unescaped text.
+func (p *Str) Pairs() ([]KV, error) {
+	if err := p.Parse(); err != nil {
+		return nil, err
+	}
+	return p.decodeAST()
+}
+
+// decodeAST walks the AST built from p.tokens32 and decodes every kv node
+// it finds into a KV. It is shared by Pairs, which builds tokens32 via a
+// normal Parse, and ParseAll, which calls it once per comma-delimited
+// segment so a decode failure in one segment can't swallow the others.
+func (p *Str) decodeAST() ([]KV, error) {
+	var pairs []KV
+	var walk func(n *node32) error
+	walk = func(n *node32) error {
+		for n != nil {
+			if n.pegRule == rulekv {
+				key := n.up
+				value, err := p.typedValue(key.next)
+				if err != nil {
+					return err
+				}
+				pairs = append(pairs, KV{Key: p.Value(key.token32), Value: value})
+			} else if n.up != nil {
+				if err := walk(n.up); err != nil {
+					return err
+				}
+			}
+			n = n.next
+		}
+		return nil
+	}
+	if err := walk(p.AST()); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// typedValue converts a kv's value node to the Go type matching the rule
+// that produced it.
+func (p *Str) typedValue(n *node32) (any, error) {
+	raw := string(p.buffer[n.begin:n.end])
+	switch n.pegRule {
+	case ruleinteger:
+		return strconv.ParseInt(raw, 10, 64)
+	case rulefloat:
+		return strconv.ParseFloat(raw, 64)
+	case rulebool:
+		return raw == "true", nil
+	case ruledatetime:
+		return time.Parse(time.RFC3339, raw)
+	case rulestring:
+		return p.Value(n.token32), nil
+	default:
+		return nil, fmt.Errorf("kv: unexpected value rule %v", rul3s[n.pegRule])
+	}
+}
+
+// MultiError collects the errors recovered by ParseAll, one per segment
+// that failed to parse or whose value failed to decode.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	var b strings.Builder
+	for i := range m {
+		b.WriteString(m[i].Error())
+	}
+	return b.String()
+}
+
+// ParseAll parses p.Buffer like Pairs, but instead of stopping at the
+// first bad kv it splits the buffer on top-level commas and parses and
+// decodes each segment on its own, skipping over the ones that fail.
+// A segment can fail either way: bad syntax (caught by sub.Parse), or a
+// value a typed rule matched but typedValue rejects, such as an integer
+// that overflows int64 or a datetime outside time.Parse's range. Either
+// kind contributes one error to MultiError without affecting any other
+// segment. It returns the KVs decoded from every segment that succeeded,
+// plus a MultiError describing each one that didn't. After ParseAll
+// returns, p.Tokens() and p.AST() reflect only the segments that both
+// parsed and decoded cleanly.
+func (p *Str) ParseAll() ([]KV, MultiError) {
+	buf := []rune(p.Buffer)
+	if len(buf) == 0 {
+		p.buffer = buf
+		p.tokens32 = tokens32{}
+		return nil, nil
+	}
+
+	var (
+		kvs    []KV
+		merged []token32
+		errs   MultiError
+	)
+	for _, seg := range splitTopLevel(buf) {
+		trimmed, leading := trimWS(seg.runes)
+		offset := seg.offset + leading
+
+		if len(trimmed) == 0 {
+			at := seg.offset
+			if at >= len(buf) {
+				at = len(buf) - 1
+			}
+			errs = append(errs, emptySegmentError(p, at))
+			continue
+		}
+
+		sub := &Str{Buffer: string(trimmed)}
+		sub.Init()
+		if err := sub.Parse(); err != nil {
+			errs = append(errs, offsetParseError(p, err.(*parseError), offset))
+			continue
+		}
+
+		segKVs, err := sub.decodeAST()
+		if err != nil {
+			errs = append(errs, &segmentDecodeError{p: p, begin: uint32(offset), end: uint32(offset + len(trimmed)), cause: err})
+			continue
+		}
+		kvs = append(kvs, segKVs...)
+
+		for _, t := range sub.Tokens() {
+			if t.pegRule == ruleline || t.pegRule == ruleexpr {
+				continue
+			}
+			merged = append(merged, token32{pegRule: t.pegRule, begin: t.begin + uint32(offset), end: t.end + uint32(offset)})
+		}
+	}
+	// tokens32.AST() folds nodes under whichever token comes last and
+	// contains them, exactly like the generated parser's own outermost
+	// "line" token does for a single Parse. Add that same wrapper here so
+	// the surviving kvs come back as siblings instead of only the last one.
+	merged = append(merged, token32{pegRule: ruleline, begin: 0, end: uint32(len(buf))})
+
+	p.buffer = buf
+	p.tokens32 = tokens32{tree: merged}
+
+	return kvs, errs
+}
+
+// offsetParseError rewrites a *parseError returned by a sub-parse of one
+// comma-delimited segment so its max token points at the segment's
+// position within p's own buffer, letting p.buffer/translatePositions
+// report accurate line/symbol info for the original input.
+func offsetParseError(p *Str, err *parseError, offset int) *parseError {
+	return &parseError{
+		p: p,
+		max: token32{
+			pegRule: err.max.pegRule,
+			begin:   err.max.begin + uint32(offset),
+			end:     err.max.end + uint32(offset),
+		},
+	}
+}
+
+// emptySegmentError reports a zero-length segment produced by a leading,
+// trailing, or doubled top-level comma (e.g. "key=1,", ",key=1",
+// "a=1,,b=2"). splitTopLevel has no "expected a kv here" production of
+// its own to catch this, so ParseAll flags it directly instead of
+// silently treating it as zero kvs, matching the error Parse() itself
+// returns for the same malformed input.
+func emptySegmentError(p *Str, at int) *parseError {
+	return &parseError{p: p, max: token32{pegRule: rulekv, begin: uint32(at), end: uint32(at)}}
+}
+
+// segmentDecodeError wraps a typedValue conversion failure with the
+// position of the segment it came from, so ParseAll's MultiError carries
+// the real strconv/time error instead of a generic syntax-error message.
+type segmentDecodeError struct {
+	p          *Str
+	begin, end uint32
+	cause      error
+}
+
+func (e *segmentDecodeError) Error() string {
+	positions := []int{int(e.begin), int(e.end)}
+	translations := translatePositions(e.p.buffer, positions)
+	return fmt.Sprintf("decode error near (line %v symbol %v - line %v symbol %v): %v\n",
+		translations[int(e.begin)].line, translations[int(e.begin)].symbol,
+		translations[int(e.end)].line, translations[int(e.end)].symbol,
+		e.cause)
+}
+
+type rawSegment struct {
+	runes  []rune
+	offset int
+}
+
+// splitTopLevel splits buf on ',' characters that sit outside a
+// single-quoted string, mirroring the separator expr uses between kvs.
+func splitTopLevel(buf []rune) []rawSegment {
+	var segs []rawSegment
+	start := 0
+	inQuote := false
+	for i := 0; i < len(buf); i++ {
+		switch {
+		case inQuote:
+			if buf[i] == '\\' && i+1 < len(buf) {
+				i++
+				continue
+			}
+			if buf[i] == '\'' {
+				inQuote = false
+			}
+		case buf[i] == '\'':
+			inQuote = true
+		case buf[i] == ',':
+			segs = append(segs, rawSegment{runes: buf[start:i], offset: start})
+			start = i + 1
+		}
+	}
+	return append(segs, rawSegment{runes: buf[start:], offset: start})
+}
+
+// trimWS strips the leading/trailing ' '/'\t' the ws rule itself would
+// have consumed around a kv, returning how many leading runes it dropped.
+func trimWS(r []rune) (trimmed []rune, leading int) {
+	start, end := 0, len(r)
+	for start < end && (r[start] == ' ' || r[start] == '\t') {
+		start++
+	}
+	for end > start && (r[end-1] == ' ' || r[end-1] == '\t') {
+		end--
+	}
+	return r[start:end], start
+}
+
+// NewStreamParser returns a Str that reads its input from r one rune at a
+// time instead of holding the whole thing in Buffer, for kv lists too
+// large or too open-ended (e.g. a network stream) to buffer up front.
+// Call Init once, then Next repeatedly until it reports io.EOF.
+func NewStreamParser(r io.Reader) *Str {
+	rr, ok := r.(io.RuneReader)
+	if !ok {
+		rr = bufio.NewReader(r)
+	}
+	return &Str{reader: rr}
+}
+
+// Next decodes the next kv from the stream, growing and then discarding
+// its rune window as it goes so memory stays bounded by the current kv
+// rather than the whole input. It returns io.EOF once the stream and any
+// buffered runes are exhausted.
+func (p *Str) Next() (KV, error) {
+	return p.next()
+}
@@ -4,6 +4,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"sort"
 	"strconv"
@@ -19,6 +20,10 @@ const (
 	ruleline
 	ruleexpr
 	rulekv
+	ruleinteger
+	rulefloat
+	rulebool
+	ruledatetime
 	rulestring
 	rulews
 )
@@ -28,6 +33,10 @@ var rul3s = [...]string{
 	"line",
 	"expr",
 	"kv",
+	"integer",
+	"float",
+	"bool",
+	"datetime",
 	"string",
 	"ws",
 }
@@ -144,11 +153,19 @@ func (t *tokens32) Tokens() []token32 {
 type Str struct {
 	Buffer string
 	buffer []rune
-	rules  [6]func() bool
+	rules  [10]func() bool
 	parse  func(rule ...int) error
 	reset  func()
 	Pretty bool
 	tokens32
+
+	// reader backs NewStreamParser: when set, Init/Next pull runes from it
+	// on demand instead of requiring the whole input up front in Buffer.
+	reader io.RuneReader
+	// next parses a single kv from the current window, growing it from
+	// reader as needed and compacting away what it consumed, reporting
+	// io.EOF once nothing is left to parse.
+	next func() (KV, error)
 }
 
 func (p *Str) Parse(rule ...int) error {
@@ -237,11 +254,16 @@ func (p *Str) Init() {
 		max = token32{}
 		position, tokenIndex = 0, 0
 
-		p.buffer = []rune(p.Buffer)
-		if len(p.buffer) == 0 || p.buffer[len(p.buffer)-1] != endSymbol {
-			p.buffer = append(p.buffer, endSymbol)
+		if p.reader != nil {
+			buffer = nil
+			p.buffer = nil
+		} else {
+			p.buffer = []rune(p.Buffer)
+			if len(p.buffer) == 0 || p.buffer[len(p.buffer)-1] != endSymbol {
+				p.buffer = append(p.buffer, endSymbol)
+			}
+			buffer = p.buffer
 		}
-		buffer = p.buffer
 	}
 	p.reset()
 
@@ -269,8 +291,36 @@ func (p *Str) Init() {
 		}
 	}
 
+	// grow pulls one more rune from p.reader into buffer, reporting false
+	// once the reader is exhausted or errors; it is a no-op, always-false
+	// in the non-streaming (Buffer-based) case.
+	grow := func() bool {
+		if p.reader == nil {
+			return false
+		}
+		r, _, err := p.reader.ReadRune()
+		if err != nil {
+			p.reader = nil
+			return false
+		}
+		buffer = append(buffer, r)
+		return true
+	}
+
+	// charAt is buffer[pos], except when streaming it grows the sliding
+	// window from p.reader on demand, returning endSymbol once pos runs
+	// past whatever the reader still has to offer.
+	charAt := func(pos uint32) rune {
+		for pos >= uint32(len(buffer)) {
+			if !grow() {
+				return endSymbol
+			}
+		}
+		return buffer[pos]
+	}
+
 	matchDot := func() bool {
-		if buffer[position] != endSymbol {
+		if charAt(position) != endSymbol {
 			position++
 			return true
 		}
@@ -278,7 +328,7 @@ func (p *Str) Init() {
 	}
 
 	/*matchChar := func(c byte) bool {
-		if buffer[position] == c {
+		if charAt(position) == rune(c) {
 			position++
 			return true
 		}
@@ -286,13 +336,89 @@ func (p *Str) Init() {
 	}*/
 
 	/*matchRange := func(lower byte, upper byte) bool {
-		if c := buffer[position]; c >= lower && c <= upper {
+		if c := charAt(position); c >= rune(lower) && c <= rune(upper) {
 			position++
 			return true
 		}
 		return false
 	}*/
 
+	p.next = func() (KV, error) {
+		for {
+			c := charAt(position)
+			if c == endSymbol {
+				return KV{}, io.EOF
+			}
+			if c != ' ' && c != '\t' && c != ',' {
+				break
+			}
+			position++
+		}
+
+		max, tokenIndex = token32{}, 0
+		if !_rules[rulekv]() {
+			parseErr := &parseError{p, max}
+
+			// rulekv restores position on failure, so the window hasn't
+			// moved past the bad segment; skip to the next top-level ','
+			// (mirroring ParseAll's splitTopLevel) so the next Next() call
+			// is guaranteed to make progress instead of repeating this
+			// same error forever.
+			inQuote := false
+			for {
+				c := charAt(position)
+				if c == endSymbol {
+					break
+				}
+				if inQuote {
+					if c == '\\' && charAt(position+1) != endSymbol {
+						position += 2
+						continue
+					}
+					if c == '\'' {
+						inQuote = false
+					}
+					position++
+					continue
+				}
+				if c == '\'' {
+					inQuote = true
+					position++
+					continue
+				}
+				if c == ',' {
+					position++
+					break
+				}
+				position++
+			}
+
+			buffer = buffer[position:]
+			p.buffer = buffer
+			position, tokenIndex, max = 0, 0, token32{}
+
+			return KV{}, parseErr
+		}
+
+		p.buffer = buffer
+		root := (&tokens32{tree: tree.tree[:tokenIndex]}).AST()
+		if root == nil || root.pegRule != rulekv || root.up == nil || root.up.next == nil {
+			return KV{}, &parseError{p, max}
+		}
+		key := root.up
+		value, err := p.typedValue(key.next)
+		if err != nil {
+			return KV{}, err
+		}
+		kv := KV{Key: p.Value(key.token32), Value: value}
+
+		buffer = buffer[position:]
+		p.buffer = buffer
+		position, tokenIndex, max = 0, 0, token32{}
+
+		return kv, nil
+	}
+
 	_rules = [...]func() bool{
 		nil,
 		/* 0 line <- <(expr !.)> */
@@ -323,7 +449,7 @@ func (p *Str) Init() {
 								position, tokenIndex = position7, tokenIndex7
 							}
 						l8:
-							if buffer[position] != rune(',') {
+							if charAt(position) != rune(',') {
 								goto l4
 							}
 							position++
@@ -366,7 +492,7 @@ func (p *Str) Init() {
 		},
 		/* 1 expr <- <(kv / (ws? ',' ws? kv))*> */
 		nil,
-		/* 2 kv <- <(string ws? '=' ws? string)> */
+		/* 2 kv <- <(string ws? '=' ws? (datetime / float / integer / bool / string))> */
 		func() bool {
 			position13, tokenIndex13 := position, tokenIndex
 			{
@@ -384,7 +510,7 @@ func (p *Str) Init() {
 					position, tokenIndex = position15, tokenIndex15
 				}
 			l16:
-				if buffer[position] != rune('=') {
+				if charAt(position) != rune('=') {
 					goto l13
 				}
 				position++
@@ -398,9 +524,37 @@ func (p *Str) Init() {
 					position, tokenIndex = position17, tokenIndex17
 				}
 			l18:
-				if !_rules[rulestring]() {
-					goto l13
+				{
+					position49, tokenIndex49 := position, tokenIndex
+					if !_rules[ruledatetime]() {
+						goto l50
+					}
+					goto l49
+				l50:
+					position, tokenIndex = position49, tokenIndex49
+					if !_rules[rulefloat]() {
+						goto l51
+					}
+					goto l49
+				l51:
+					position, tokenIndex = position49, tokenIndex49
+					if !_rules[ruleinteger]() {
+						goto l52
+					}
+					goto l49
+				l52:
+					position, tokenIndex = position49, tokenIndex49
+					if !_rules[rulebool]() {
+						goto l53
+					}
+					goto l49
+				l53:
+					position, tokenIndex = position49, tokenIndex49
+					if !_rules[rulestring]() {
+						goto l13
+					}
 				}
+			l49:
 				add(rulekv, position14)
 			}
 			return true
@@ -408,48 +562,413 @@ func (p *Str) Init() {
 			position, tokenIndex = position13, tokenIndex13
 			return false
 		},
-		/* 3 string <- <([a-z] / [0-9])+> */
+		/* 3 integer <- <('-'? [0-9]+ ![a-z0-9])> */
 		func() bool {
-			position19, tokenIndex19 := position, tokenIndex
+			position55, tokenIndex55 := position, tokenIndex
 			{
-				position20 := position
+				position56 := position
+				{
+					position57, tokenIndex57 := position, tokenIndex
+					if charAt(position) != rune('-') {
+						goto l57
+					}
+					position++
+					goto l58
+				l57:
+					position, tokenIndex = position57, tokenIndex57
+				}
+			l58:
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l55
+				}
+				position++
+			l59:
+				{
+					position60, tokenIndex60 := position, tokenIndex
+					if c := charAt(position); c < rune('0') || c > rune('9') {
+						goto l60
+					}
+					position++
+					goto l59
+				l60:
+					position, tokenIndex = position60, tokenIndex60
+				}
+				{
+					position87, tokenIndex87 := position, tokenIndex
+					if c := charAt(position); (c >= rune('a') && c <= rune('z')) || (c >= rune('0') && c <= rune('9')) {
+						position++
+						goto l55
+					}
+					position, tokenIndex = position87, tokenIndex87
+				}
+				add(ruleinteger, position56)
+			}
+			return true
+		l55:
+			position, tokenIndex = position55, tokenIndex55
+			return false
+		},
+		/* 4 float <- <('-'? [0-9]+ '.' [0-9]+ ![a-z0-9])> */
+		func() bool {
+			position62, tokenIndex62 := position, tokenIndex
+			{
+				position63 := position
+				{
+					position64, tokenIndex64 := position, tokenIndex
+					if charAt(position) != rune('-') {
+						goto l64
+					}
+					position++
+					goto l65
+				l64:
+					position, tokenIndex = position64, tokenIndex64
+				}
+			l65:
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l62
+				}
+				position++
+			l66:
+				{
+					position67, tokenIndex67 := position, tokenIndex
+					if c := charAt(position); c < rune('0') || c > rune('9') {
+						goto l67
+					}
+					position++
+					goto l66
+				l67:
+					position, tokenIndex = position67, tokenIndex67
+				}
+				if charAt(position) != rune('.') {
+					goto l62
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l62
+				}
+				position++
+			l68:
+				{
+					position69, tokenIndex69 := position, tokenIndex
+					if c := charAt(position); c < rune('0') || c > rune('9') {
+						goto l69
+					}
+					position++
+					goto l68
+				l69:
+					position, tokenIndex = position69, tokenIndex69
+				}
+				{
+					position88, tokenIndex88 := position, tokenIndex
+					if c := charAt(position); (c >= rune('a') && c <= rune('z')) || (c >= rune('0') && c <= rune('9')) {
+						position++
+						goto l62
+					}
+					position, tokenIndex = position88, tokenIndex88
+				}
+				add(rulefloat, position63)
+			}
+			return true
+		l62:
+			position, tokenIndex = position62, tokenIndex62
+			return false
+		},
+		/* 5 bool <- <(('true' / 'false') ![a-z0-9])> */
+		func() bool {
+			position70, tokenIndex70 := position, tokenIndex
+			{
+				position71 := position
+				{
+					position72, tokenIndex72 := position, tokenIndex
+					if charAt(position) != rune('t') {
+						goto l73
+					}
+					position++
+					if charAt(position) != rune('r') {
+						goto l73
+					}
+					position++
+					if charAt(position) != rune('u') {
+						goto l73
+					}
+					position++
+					if charAt(position) != rune('e') {
+						goto l73
+					}
+					position++
+					goto l72
+				l73:
+					position, tokenIndex = position72, tokenIndex72
+					if charAt(position) != rune('f') {
+						goto l70
+					}
+					position++
+					if charAt(position) != rune('a') {
+						goto l70
+					}
+					position++
+					if charAt(position) != rune('l') {
+						goto l70
+					}
+					position++
+					if charAt(position) != rune('s') {
+						goto l70
+					}
+					position++
+					if charAt(position) != rune('e') {
+						goto l70
+					}
+					position++
+				}
+			l72:
 				{
-					position23, tokenIndex23 := position, tokenIndex
-					if c := buffer[position]; c < rune('a') || c > rune('z') {
-						goto l24
+					position89, tokenIndex89 := position, tokenIndex
+					if c := charAt(position); (c >= rune('a') && c <= rune('z')) || (c >= rune('0') && c <= rune('9')) {
+						position++
+						goto l70
+					}
+					position, tokenIndex = position89, tokenIndex89
+				}
+				add(rulebool, position71)
+			}
+			return true
+		l70:
+			position, tokenIndex = position70, tokenIndex70
+			return false
+		},
+		/* 6 datetime <- <([0-9] [0-9] [0-9] [0-9] '-' [0-9] [0-9] '-' [0-9] [0-9] 'T' [0-9] [0-9] ':' [0-9] [0-9] ':' [0-9] [0-9] ('Z' / (('+' / '-') [0-9] [0-9] ':' [0-9] [0-9])) ![a-z0-9])> */
+		func() bool {
+			position80, tokenIndex80 := position, tokenIndex
+			{
+				position81 := position
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if charAt(position) != rune('-') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if charAt(position) != rune('-') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if charAt(position) != rune('T') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if charAt(position) != rune(':') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if charAt(position) != rune(':') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				if c := charAt(position); c < rune('0') || c > rune('9') {
+					goto l80
+				}
+				position++
+				{
+					position82, tokenIndex82 := position, tokenIndex
+					if charAt(position) != rune('Z') {
+						goto l83
+					}
+					position++
+					goto l82
+				l83:
+					position, tokenIndex = position82, tokenIndex82
+					{
+						position84, tokenIndex84 := position, tokenIndex
+						if charAt(position) != rune('+') {
+							goto l85
+						}
+						position++
+						goto l84
+					l85:
+						position, tokenIndex = position84, tokenIndex84
+						if charAt(position) != rune('-') {
+							goto l80
+						}
+						position++
+					}
+				l84:
+					if c := charAt(position); c < rune('0') || c > rune('9') {
+						goto l80
+					}
+					position++
+					if c := charAt(position); c < rune('0') || c > rune('9') {
+						goto l80
 					}
 					position++
-					goto l23
-				l24:
-					position, tokenIndex = position23, tokenIndex23
-					if c := buffer[position]; c < rune('0') || c > rune('9') {
-						goto l19
+					if charAt(position) != rune(':') {
+						goto l80
+					}
+					position++
+					if c := charAt(position); c < rune('0') || c > rune('9') {
+						goto l80
+					}
+					position++
+					if c := charAt(position); c < rune('0') || c > rune('9') {
+						goto l80
 					}
 					position++
 				}
-			l23:
-			l21:
+			l82:
 				{
-					position22, tokenIndex22 := position, tokenIndex
+					position90, tokenIndex90 := position, tokenIndex
+					if c := charAt(position); (c >= rune('a') && c <= rune('z')) || (c >= rune('0') && c <= rune('9')) {
+						position++
+						goto l80
+					}
+					position, tokenIndex = position90, tokenIndex90
+				}
+				add(ruledatetime, position81)
+			}
+			return true
+		l80:
+			position, tokenIndex = position80, tokenIndex80
+			return false
+		},
+		/* 7 string <- <(('\'' (('\\' .) / (!'\'' .))* '\'') / ([a-z] / [0-9])+)> */
+		func() bool {
+			position19, tokenIndex19 := position, tokenIndex
+			{
+				position20 := position
+				{
+					position33, tokenIndex33 := position, tokenIndex
+					if charAt(position) != rune('\'') {
+						goto l34
+					}
+					position++
+				l35:
+					{
+						position36, tokenIndex36 := position, tokenIndex
+						{
+							position37, tokenIndex37 := position, tokenIndex
+							if charAt(position) != rune('\\') {
+								goto l38
+							}
+							position++
+							if !matchDot() {
+								goto l38
+							}
+							goto l37
+						l38:
+							position, tokenIndex = position37, tokenIndex37
+							{
+								position39, tokenIndex39 := position, tokenIndex
+								if charAt(position) != rune('\'') {
+									goto l40
+								}
+								position++
+								goto l36
+							l40:
+								position, tokenIndex = position39, tokenIndex39
+							}
+							if !matchDot() {
+								goto l36
+							}
+						}
+					l37:
+						goto l35
+					l36:
+						position, tokenIndex = position36, tokenIndex36
+					}
+					if charAt(position) != rune('\'') {
+						goto l34
+					}
+					position++
+					goto l86
+				l34:
+					position, tokenIndex = position33, tokenIndex33
 					{
-						position25, tokenIndex25 := position, tokenIndex
-						if c := buffer[position]; c < rune('a') || c > rune('z') {
-							goto l26
+						position23, tokenIndex23 := position, tokenIndex
+						if c := charAt(position); c < rune('a') || c > rune('z') {
+							goto l24
 						}
 						position++
-						goto l25
-					l26:
-						position, tokenIndex = position25, tokenIndex25
-						if c := buffer[position]; c < rune('0') || c > rune('9') {
-							goto l22
+						goto l23
+					l24:
+						position, tokenIndex = position23, tokenIndex23
+						if c := charAt(position); c < rune('0') || c > rune('9') {
+							goto l19
 						}
 						position++
 					}
-				l25:
-					goto l21
-				l22:
-					position, tokenIndex = position22, tokenIndex22
+				l23:
+				l21:
+					{
+						position22, tokenIndex22 := position, tokenIndex
+						{
+							position25, tokenIndex25 := position, tokenIndex
+							if c := charAt(position); c < rune('a') || c > rune('z') {
+								goto l26
+							}
+							position++
+							goto l25
+						l26:
+							position, tokenIndex = position25, tokenIndex25
+							if c := charAt(position); c < rune('0') || c > rune('9') {
+								goto l22
+							}
+							position++
+						}
+					l25:
+						goto l21
+					l22:
+						position, tokenIndex = position22, tokenIndex22
+					}
 				}
+			l86:
 				add(rulestring, position20)
 			}
 			return true
@@ -457,7 +976,7 @@ func (p *Str) Init() {
 			position, tokenIndex = position19, tokenIndex19
 			return false
 		},
-		/* 4 ws <- <(' ' / '\t')*> */
+		/* 8 ws <- <(' ' / '\t')*> */
 		func() bool {
 			{
 				position28 := position
@@ -466,14 +985,14 @@ func (p *Str) Init() {
 					position30, tokenIndex30 := position, tokenIndex
 					{
 						position31, tokenIndex31 := position, tokenIndex
-						if buffer[position] != rune(' ') {
+						if charAt(position) != rune(' ') {
 							goto l32
 						}
 						position++
 						goto l31
 					l32:
 						position, tokenIndex = position31, tokenIndex31
-						if buffer[position] != rune('\t') {
+						if charAt(position) != rune('\t') {
 							goto l30
 						}
 						position++
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValue(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bare alnum", "key=abc123", "abc123"},
+		{"quoted with spaces and commas", "key='a, b c'", "a, b c"},
+		{"quoted with equals", "key='a=b'", "a=b"},
+		{"quoted escaped quote", `key='it\'s here'`, "it's here"},
+		{"quoted escaped backslash", `key='a\\b'`, `a\b`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := &Str{Buffer: c.in}
+			p.Init()
+			if err := p.Parse(); err != nil {
+				t.Fatalf("Parse(%q): %v", c.in, err)
+			}
+			var got string
+			for _, tok := range p.Tokens() {
+				if tok.pegRule == rulestring {
+					got = p.Value(tok)
+				}
+			}
+			if got != c.want {
+				t.Errorf("Value = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPairs(t *testing.T) {
+	p := &Str{Buffer: "i=-42, f=3.14, b=true, d=2024-01-02T15:04:05Z, s=hello"}
+	p.Init()
+	kvs, err := p.Pairs()
+	if err != nil {
+		t.Fatalf("Pairs(): %v", err)
+	}
+
+	want := map[string]any{
+		"i": int64(-42),
+		"f": 3.14,
+		"b": true,
+		"d": time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		"s": "hello",
+	}
+	if len(kvs) != len(want) {
+		t.Fatalf("got %d pairs, want %d: %+v", len(kvs), len(want), kvs)
+	}
+	for _, kv := range kvs {
+		wantV, ok := want[kv.Key]
+		if !ok {
+			t.Errorf("unexpected key %q", kv.Key)
+			continue
+		}
+		if wantT, ok := wantV.(time.Time); ok {
+			if gotT, ok := kv.Value.(time.Time); !ok || !gotT.Equal(wantT) {
+				t.Errorf("%s: got %v, want %v", kv.Key, kv.Value, wantT)
+			}
+			continue
+		}
+		if kv.Value != wantV {
+			t.Errorf("%s: got %#v, want %#v", kv.Key, kv.Value, wantV)
+		}
+	}
+}
+
+// TestPairsBarewordBoundary guards against the typed-value alternation
+// matching a numeric/bool prefix and then failing outright instead of
+// falling back to a plain string when extra bareword characters follow.
+func TestPairsBarewordBoundary(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"key=123abc", "123abc"},
+		{"key=truely", "truely"},
+		{"key=falsehood", "falsehood"},
+		{"key=trueish", "trueish"},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			p := &Str{Buffer: c.in}
+			p.Init()
+			kvs, err := p.Pairs()
+			if err != nil {
+				t.Fatalf("Pairs(%q): %v", c.in, err)
+			}
+			if len(kvs) != 1 || kvs[0].Value != c.want {
+				t.Errorf("Pairs(%q) = %+v, want [{key %q}]", c.in, kvs, c.want)
+			}
+		})
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	p := &Str{Buffer: "a=1, ???, b=2, =also bad, c=3"}
+	kvs, errs := p.ParseAll()
+
+	wantKeys := []string{"a", "b", "c"}
+	if len(kvs) != len(wantKeys) {
+		t.Fatalf("got %d kvs, want %d: %+v", len(kvs), len(wantKeys), kvs)
+	}
+	for i, k := range wantKeys {
+		if kvs[i].Key != k {
+			t.Errorf("kvs[%d].Key = %q, want %q", i, kvs[i].Key, k)
+		}
+	}
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+}
+
+func TestParseAllAllGood(t *testing.T) {
+	p := &Str{Buffer: "a=1, b=2"}
+	kvs, errs := p.ParseAll()
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(kvs) != 2 {
+		t.Fatalf("got %d kvs, want 2: %+v", len(kvs), kvs)
+	}
+}
+
+// TestParseAllSemanticFailure guards against a typed-value decode failure
+// (here, an integer overflowing int64) swallowing every other kv in the
+// document: only the offending segment should be dropped, and its error
+// must carry the real strconv/time message rather than a generic
+// "Unknown" syntax error.
+func TestParseAllSemanticFailure(t *testing.T) {
+	p := &Str{Buffer: "a=1, big=99999999999999999999, b=2"}
+	kvs, errs := p.ParseAll()
+
+	wantKeys := []string{"a", "b"}
+	if len(kvs) != len(wantKeys) {
+		t.Fatalf("got %d kvs, want %d: %+v", len(kvs), len(wantKeys), kvs)
+	}
+	for i, k := range wantKeys {
+		if kvs[i].Key != k {
+			t.Errorf("kvs[%d].Key = %q, want %q", i, kvs[i].Key, k)
+		}
+	}
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "value out of range") {
+		t.Errorf("error = %q, want it to contain the underlying strconv message", errs[0].Error())
+	}
+}
+
+// TestParseAllStrayComma guards against a leading, trailing, or doubled
+// top-level comma being silently accepted as zero kvs: plain Parse()
+// rejects the same input, and ParseAll must flag it too instead of going
+// quiet about it.
+func TestParseAllStrayComma(t *testing.T) {
+	for _, in := range []string{"key=1,", ",key=1", "key1=1,,key2=2"} {
+		t.Run(in, func(t *testing.T) {
+			p := &Str{Buffer: in}
+			_, errs := p.ParseAll()
+			if len(errs) == 0 {
+				t.Errorf("ParseAll(%q): expected an error for the stray comma, got none", in)
+			}
+		})
+	}
+}
+
+func TestStreamParserNext(t *testing.T) {
+	sp := NewStreamParser(strings.NewReader("a=1, b=2, c=3"))
+	sp.Init()
+
+	var got []KV
+	for {
+		kv, err := sp.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next(): %v", err)
+		}
+		got = append(got, kv)
+	}
+
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d kvs, want %d: %+v", len(got), len(want), got)
+	}
+	for i, k := range want {
+		if got[i].Key != k {
+			t.Errorf("got[%d].Key = %q, want %q", i, got[i].Key, k)
+		}
+	}
+}
+
+// TestStreamParserNextRecoversFromBadSegment guards against Next() getting
+// stuck replaying the same non-advancing parseError forever once it hits a
+// malformed kv: it must skip the bad segment and keep making progress
+// toward io.EOF.
+func TestStreamParserNextRecoversFromBadSegment(t *testing.T) {
+	sp := NewStreamParser(strings.NewReader("a=1, ???, b=2"))
+	sp.Init()
+
+	const maxCalls = 10
+	var got []KV
+	sawErr := false
+	for i := 0; i < maxCalls; i++ {
+		kv, err := sp.Next()
+		if err == io.EOF {
+			if !sawErr {
+				t.Fatal("reached io.EOF without ever seeing the bad segment's error")
+			}
+			if len(got) != 2 || got[0].Key != "a" || got[1].Key != "b" {
+				t.Fatalf("got %+v, want a, b", got)
+			}
+			return
+		}
+		if err != nil {
+			sawErr = true
+			continue
+		}
+		got = append(got, kv)
+	}
+	t.Fatalf("Next() did not reach io.EOF within %d calls", maxCalls)
+}
+
+func TestParseQuotedString(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{"unterminated quote", "key='abc", true},
+		{"quote with trailing bareword", "key='abc'xyz", true},
+		{"empty quoted value", "key=''", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := &Str{Buffer: c.in}
+			p.Init()
+			err := p.Parse()
+			if (err != nil) != c.wantErr {
+				t.Errorf("Parse(%q) err = %v, wantErr %v", c.in, err, c.wantErr)
+			}
+		})
+	}
+}